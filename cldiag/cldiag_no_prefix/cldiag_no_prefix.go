@@ -14,30 +14,58 @@ import (
 )
 
 func Warn(format string, fmtArgs ...interface{}) {
-	cldiag.Warn2("", format, fmtArgs)
+	cldiag.Warn2("", format, fmtArgs...)
 }
 func Warn2(tag, format string, fmtArgs ...interface{}) {
 	cldiag.Warn2(tag, format, fmtArgs...)
 }
 
 func WarnIf(skipIfNil interface{}, format string, fmtArgs ...interface{}) {
-	cldiag.WarnIf2(skipIfNil, "", format, fmtArgs)
+	cldiag.WarnIf2(skipIfNil, "", format, fmtArgs...)
 }
 func WarnIf2(skipIfNil interface{}, tag, format string, fmtArgs ...interface{}) {
-	cldiag.WarnIf2(skipIfNil, tag, format, fmtArgs)
+	cldiag.WarnIf2(skipIfNil, tag, format, fmtArgs...)
 }
 
 func Die(format string, fmtArgs ...interface{}) {
 	cldiag.Die(format, fmtArgs...)
 }
 func Die2(tag, format string, fmtArgs ...interface{}) {
-	cldiag.Die2(tag, format, fmtArgs)
+	cldiag.Die2(tag, format, fmtArgs...)
 }
 
 func DieIf(skipIfNil interface{}, format string, fmtArgs ...interface{}) {
-	cldiag.DieIf2(skipIfNil, "", format, fmtArgs)
+	cldiag.DieIf2(skipIfNil, "", format, fmtArgs...)
 }
 
 func DieIf2(skipIfNil interface{}, tag, format string, fmtArgs ...interface{}) {
-	cldiag.DieIf2(skipIfNil, tag, format, fmtArgs)
+	cldiag.DieIf2(skipIfNil, tag, format, fmtArgs...)
+}
+
+func Info(format string, fmtArgs ...interface{}) {
+	cldiag.Info2("", format, fmtArgs...)
+}
+func Info2(tag, format string, fmtArgs ...interface{}) {
+	cldiag.Info2(tag, format, fmtArgs...)
+}
+
+func Notice(format string, fmtArgs ...interface{}) {
+	cldiag.Notice2("", format, fmtArgs...)
+}
+func Notice2(tag, format string, fmtArgs ...interface{}) {
+	cldiag.Notice2(tag, format, fmtArgs...)
+}
+
+func Error(format string, fmtArgs ...interface{}) {
+	cldiag.Error2("", format, fmtArgs...)
+}
+func Error2(tag, format string, fmtArgs ...interface{}) {
+	cldiag.Error2(tag, format, fmtArgs...)
+}
+
+func Critical(format string, fmtArgs ...interface{}) {
+	cldiag.Critical2("", format, fmtArgs...)
+}
+func Critical2(tag, format string, fmtArgs ...interface{}) {
+	cldiag.Critical2(tag, format, fmtArgs...)
 }
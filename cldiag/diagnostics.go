@@ -36,6 +36,19 @@
 // When calling os.Exit(), the default exit status is 3 if any warnings were
 // reported, or 2 if none were.  Programs can call SetExitStatus to change this.
 //
+// For finer-grained needs than these three levels, see Debug[2], Info[2],
+// Notice[2], Error[2], Critical[2], Alert[2] and Emergency[2], which write at
+// the matching entry of the eight syslog/sd-daemon severities (Severity).
+// SetLevel raises the threshold below which diagnostics are dropped
+// entirely; Warn[If][2] only counts messages that actually get through it.
+// Where a diagnostic ends up is controlled by the Sink interface -- SetSink
+// can install a JSON sink, a sink that just records Entries for a test, or a
+// fan-out of several sinks -- and SetSeverityWriter can send one severity's
+// messages to a different io.Writer than the rest. How it is rendered is
+// controlled separately, by the Encoder interface: SetEncoder can switch
+// from the default human-readable TextEncoder to JSONEncoder or
+// LogfmtEncoder for consumption by a log shipper.
+//
 // This module has a subpackage named cldiag_no_prefix which provides wrappers
 // for the Warn[If][2] and Die[If][2] functions. It is intended to be imported
 // without a prefix, like this:
@@ -153,9 +166,47 @@ func Warn(format string, fmtArgs ...interface{}) {
 }
 
 // Warn2 writes a warning message.  It takes an optional ‘tag’ argument.
+//
+// If SetLevel has raised the threshold above SeverityWarning, the message is
+// dropped and nWarnings is NOT incremented.  SuppressWarning(tag) drops it
+// the same way.  PromoteWarning(tag) or SetWarningsAreErrors(true) emits it
+// at SeverityError with tag "error" (or the caller's tag, if non-empty)
+// instead, and SetMaxWarnings aborts the program once too many have been
+// reported; see those functions and AtExit.
 func Warn2(tag, format string, fmtArgs ...interface{}) {
+	warn(tag, nil, format, fmtArgs...)
+}
+
+// warn is the shared implementation behind Warn2 and WarnIf2; src is the
+// skipIfNil value passed to WarnIf2 (nil from Warn2), carried through so a
+// *clerrs.CannotError can be rendered as structured fields by JSONEncoder
+// and LogfmtEncoder instead of a flattened string.
+func warn(tag string, src interface{}, format string, fmtArgs ...interface{}) {
+	warnLoc(nil, tag, src, format, fmtArgs...)
+}
+
+// warnLoc is warn with an optional source Loc (used by Warn2At), so located
+// and unlocated warnings go through the same SuppressWarning/PromoteWarning/
+// SetWarningsAreErrors/SetMaxWarnings policy.
+func warnLoc(loc *Loc, tag string, src interface{}, format string, fmtArgs ...interface{}) {
+	if suppressedTags[tag] {
+		return
+	}
+	sev, effectiveTag := SeverityWarning, tag
+	if warnAsError(tag) {
+		sev = SeverityError
+		if effectiveTag == "" {
+			effectiveTag = "error"
+		}
+	}
+	if !severityEnabled(sev) {
+		return
+	}
 	nWarnings++
-	WriteMessage2(tag, format, fmtArgs...)
+	emitEntry(sev, loc, effectiveTag, src, format, fmtArgs...)
+	if maxWarnings > 0 && nWarnings == maxWarnings+1 {
+		dieLoc(loc, "", nil, "too many warnings (%d); aborting", maxWarnings)
+	}
 }
 
 // WarnIf writes a warning message if (and only if) its first argument is non-nil.
@@ -170,12 +221,13 @@ func WarnIf(skipIfNil interface{}, format string, fmtArgs ...interface{}) {
 //
 // As a special case, WarnIf2(x,tag,"") is equivalent to WarnIf2(x,tag,"%s",x).
 func WarnIf2(skipIfNil interface{}, tag, format string, fmtArgs ...interface{}) {
-	if skipIfNil != nil {
-		if format == "" {
-			Warn2(tag, "%s", skipIfNil)
-		} else {
-			Warn2(tag, format, fmtArgs...)
-		}
+	if skipIfNil == nil {
+		return
+	}
+	if format == "" {
+		warn(tag, skipIfNil, "%s", skipIfNil)
+	} else {
+		warn(tag, skipIfNil, format, fmtArgs...)
 	}
 }
 
@@ -189,9 +241,25 @@ func Die(format string, fmtArgs ...interface{}) {
 
 // Die2 writes a fatal error message (if and only if format is non-empty) and
 // calls os.Exit.  It takes an optional ‘tag’ argument.
+//
+// The message is written at SeverityCritical, so SetLevel("alert") or higher
+// will silence it; os.Exit is still called regardless.
 func Die2(tag, format string, fmtArgs ...interface{}) {
+	die(tag, nil, format, fmtArgs...)
+}
+
+// die is the shared implementation behind Die2 and DieIf2; src is the
+// skipIfNil value passed to DieIf2 (nil from Die2), carried through for the
+// same reason as in warn.
+func die(tag string, src interface{}, format string, fmtArgs ...interface{}) {
+	dieLoc(nil, tag, src, format, fmtArgs...)
+}
+
+// dieLoc is die with an optional source Loc (used by Die2At and warnLoc's
+// SetMaxWarnings abort).
+func dieLoc(loc *Loc, tag string, src interface{}, format string, fmtArgs ...interface{}) {
 	if format != "" {
-		WriteMessage2(tag, format, fmtArgs...)
+		emitEntry(SeverityCritical, loc, tag, src, format, fmtArgs...)
 	}
 	//
 	os.Exit(dieExitStatus())
@@ -204,9 +272,9 @@ func DieIf(skipIfNil interface{}, format string, fmtArgs ...interface{}) {
 	if skipIfNil == nil {
 		return
 	} else if format == "" {
-		Die2("", "%s", skipIfNil)
+		die("", skipIfNil, "%s", skipIfNil)
 	} else {
-		Die2("", format, fmtArgs...)
+		die("", skipIfNil, format, fmtArgs...)
 	}
 }
 
@@ -218,9 +286,9 @@ func DieIf2(skipIfNil interface{}, tag, format string, fmtArgs ...interface{}) {
 	if skipIfNil == nil {
 		return
 	} else if format == "" {
-		Die2(tag, "%s", skipIfNil)
+		die(tag, skipIfNil, "%s", skipIfNil)
 	} else {
-		Die2(tag, format, fmtArgs...)
+		die(tag, skipIfNil, format, fmtArgs...)
 	}
 }
 
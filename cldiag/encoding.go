@@ -0,0 +1,170 @@
+package cldiag
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/*================================== Record ==================================*/
+
+// A Record is what an Encoder turns into a line of output. It carries
+// everything an Entry knows, flattened into a form that's easy for an
+// Encoder to render without caring about sinks or severities-as-ints.
+//
+// When Fields is set (because the diagnostic's source implemented
+// StructuredFields), JSONEncoder and LogfmtEncoder render Fields instead of
+// Message -- not both -- since the two say the same thing and the whole
+// point of a structured record is not to flatten it back into one string.
+// Message is still populated in this case (TextEncoder has no use for
+// Fields and needs it), just not emitted by those Encoders.
+type Record struct {
+	Prog          string                 `json:"prog"`
+	Severity      string                 `json:"severity"`
+	Tag           string                 `json:"tag,omitempty"`
+	Message       string                 `json:"message,omitempty"`
+	Loc           *Loc                   `json:"loc,omitempty"`
+	WarningsSoFar int                    `json:"warnings_so_far,omitempty"`
+	Fields        map[string]interface{} `json:"fields,omitempty"`
+}
+
+func (e Entry) toRecord() Record {
+	return Record{
+		Prog:          e.Prog,
+		Severity:      e.Severity.String(),
+		Tag:           e.Tag,
+		Message:       e.Message,
+		Loc:           e.Loc,
+		WarningsSoFar: e.WarningsSoFar,
+		Fields:        e.Fields,
+	}
+}
+
+/*================================== Encoder ==================================*/
+
+// An Encoder renders a Record as one line of output text (with no trailing
+// newline). SetEncoder installs one for every future diagnostic.
+type Encoder interface {
+	Encode(Record) string
+}
+
+// TextEncoder reproduces this package's original human-readable rendering:
+// "prog[ tag]: message", or "prog: file:line:col[: tag]: message" for a
+// located diagnostic, optionally preceded by an sd-daemon "<N>" prefix. It is
+// the default Encoder, and is also always used for the /dev/tty fallback
+// path, regardless of what SetEncoder last installed.
+type TextEncoder struct{}
+
+func (TextEncoder) Encode(r Record) string {
+	var b strings.Builder
+	if sdPrefixesEnabled {
+		sev, _ := ParseSeverity(r.Severity)
+		b.WriteString(sev.sdPrefix())
+	}
+	b.WriteString(r.Prog)
+	if r.Loc != nil {
+		b.WriteString(": ")
+		b.WriteString(renderLoc(*r.Loc))
+		if r.Tag != "" {
+			b.WriteString(": ")
+			b.WriteString(r.Tag)
+		}
+	} else if r.Tag != "" {
+		b.WriteRune(' ')
+		b.WriteString(r.Tag)
+	}
+	b.WriteString(": ")
+	b.WriteString(r.Message)
+	return b.String()
+}
+
+// JSONEncoder renders each Record as one line of JSON.
+type JSONEncoder struct{}
+
+func (JSONEncoder) Encode(r Record) string {
+	if r.Fields != nil {
+		r.Message = ""
+	}
+	bytes, err := json.Marshal(r)
+	if err != nil {
+		// Should be unreachable (Record is all marshalable types), but fall
+		// back to TextEncoder rather than lose the diagnostic.
+		return TextEncoder{}.Encode(r)
+	}
+	return string(bytes)
+}
+
+// LogfmtEncoder renders each Record as space-separated key=value pairs, in
+// the style popularised by Heroku/logfmt: prog=..., severity=..., etc.
+type LogfmtEncoder struct{}
+
+func (LogfmtEncoder) Encode(r Record) string {
+	var b strings.Builder
+	writeLogfmtPair(&b, "prog", r.Prog)
+	writeLogfmtPair(&b, "severity", r.Severity)
+	if r.Tag != "" {
+		writeLogfmtPair(&b, "tag", r.Tag)
+	}
+	if r.Loc != nil {
+		writeLogfmtPair(&b, "loc", renderLoc(*r.Loc))
+	}
+	if r.Fields == nil {
+		writeLogfmtPair(&b, "message", r.Message)
+	}
+	if r.WarningsSoFar > 0 {
+		writeLogfmtPair(&b, "warnings_so_far", strconv.Itoa(r.WarningsSoFar))
+	}
+	for _, k := range sortedKeys(r.Fields) {
+		writeLogfmtPair(&b, k, fmt.Sprintf("%v", r.Fields[k]))
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+func writeLogfmtPair(b *strings.Builder, key, val string) {
+	b.WriteString(key)
+	b.WriteRune('=')
+	if strings.ContainsAny(val, " \t\"=") {
+		fmt.Fprintf(b, "%q", val)
+	} else {
+		b.WriteString(val)
+	}
+	b.WriteRune(' ')
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	for i := 1; i < len(keys); i++ {
+		for j := i; j > 0 && keys[j-1] > keys[j]; j-- {
+			keys[j-1], keys[j] = keys[j], keys[j-1]
+		}
+	}
+	return keys
+}
+
+/*=============================== Installing an Encoder ===============================*/
+
+var currentEncoder Encoder = chooseDefaultEncoder()
+
+// chooseDefaultEncoder auto-selects JSONEncoder when stderr is not a
+// terminal and CLDIAG_FORMAT=json is set in the environment, so the same
+// binary is friendly to a human at a terminal and to a log shipper under
+// systemd/Kubernetes; otherwise it returns TextEncoder{}.
+func chooseDefaultEncoder() Encoder {
+	if os.Getenv("CLDIAG_FORMAT") == "json" && !isTerminal(os.Stderr) {
+		return JSONEncoder{}
+	}
+	return TextEncoder{}
+}
+
+// SetEncoder installs e as the Encoder used to render every future
+// diagnostic, and returns the previous one.
+func SetEncoder(e Encoder) Encoder {
+	old := currentEncoder
+	currentEncoder = e
+	return old
+}
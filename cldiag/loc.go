@@ -0,0 +1,171 @@
+package cldiag
+
+import (
+	"fmt"
+	"os"
+)
+
+/*==================================== Loc ====================================*/
+
+// A Loc identifies a span of source text, eg the token a parser is
+// complaining about.  EndLine and EndCol are optional (zero if unknown or
+// irrelevant); Col, EndCol are 1-based, as is conventional for compilers.
+type Loc struct {
+	File            string
+	Line, Col       int
+	EndLine, EndCol int
+}
+
+// renderLoc is called to turn a Loc into the text put between "prog: " and
+// "tag: message"; SetLocRenderer can replace it, eg to get MSVC-style
+// "file(line,col)" instead of the default GNU-style "file:line:col".
+var renderLoc = func(l Loc) string {
+	switch {
+	case l.Line <= 0:
+		return l.File
+	case l.Col <= 0:
+		return fmt.Sprintf("%s:%d", l.File, l.Line)
+	default:
+		return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Col)
+	}
+}
+
+// SetLocRenderer installs a new function for converting a Loc to text and
+// returns the previous one.
+func SetLocRenderer(f func(Loc) string) (old func(Loc) string) {
+	old = renderLoc
+	renderLoc = f
+	return old
+}
+
+// String renders l using the current renderLoc function.
+func (l Loc) String() string { return renderLoc(l) }
+
+/*============================ Located() error wrapper ============================*/
+
+// A locatedError decorates a lower-level error with a Loc, so that a
+// CannotError returned deep inside a parser can be given a location at the
+// call site that actually knows where it was in the source.
+type locatedError struct {
+	Loc Loc
+	Err error
+}
+
+// Located wraps err so that its Error() text is prefixed with loc, rendered
+// by the current renderLoc function.  err may be nil, in which case Located
+// returns nil.
+func Located(err error, loc Loc) error {
+	if err == nil {
+		return nil
+	}
+	return &locatedError{Loc: loc, Err: err}
+}
+
+func (e *locatedError) Error() string {
+	return renderLoc(e.Loc) + ": " + e.Err.Error()
+}
+
+func (e *locatedError) Unwrap() error { return e.Err }
+
+/*============================== At-variant diagnostics ==============================*/
+
+func renderEntryAt(sev Severity, loc Loc, tag, format string, fmtArgs ...interface{}) Entry {
+	l := loc
+	return Entry{
+		Prog:          msgPrefix,
+		Severity:      sev,
+		Tag:           tag,
+		Message:       fmt.Sprintf(format, fmtArgs...),
+		Loc:           &l,
+		WarningsSoFar: nWarnings,
+		Writer:        writerForSeverity(sev),
+	}
+}
+
+// WriteMessage2At writes an informational message located at loc, in the
+// form "prog: file:line:col: tag: message".
+func WriteMessage2At(loc Loc, tag, format string, fmtArgs ...interface{}) {
+	emitEntry(SeverityInfo, &loc, tag, nil, format, fmtArgs...)
+}
+
+// Warn2At writes a located warning message. It goes through the same
+// warn-policy path as Warn2 -- SuppressWarning, PromoteWarning,
+// SetWarningsAreErrors and SetMaxWarnings all apply to located warnings
+// exactly as they do to unlocated ones -- and likewise only increments
+// NumberOfWarnings if the message was not dropped.
+func Warn2At(loc Loc, tag, format string, fmtArgs ...interface{}) {
+	warnLoc(&loc, tag, nil, format, fmtArgs...)
+}
+
+// Die2At writes a located fatal error message (unless format == "") and
+// calls os.Exit, exactly like Die2 but with a source location.
+func Die2At(loc Loc, tag, format string, fmtArgs ...interface{}) {
+	dieLoc(&loc, tag, nil, format, fmtArgs...)
+}
+
+/*============================== Caret rendering ==============================*/
+
+// A LineFetcher returns the source text of the given line of file, and
+// whether it was available at all.
+type LineFetcher func(file string, line int) (text string, ok bool)
+
+var lineFetcher LineFetcher
+
+// SetLineFetcher installs the callback used to fetch source lines for caret
+// (^~~~) rendering under the At-variant diagnostics, and returns the
+// previous one.  Until one is installed, no carets are ever drawn.
+func SetLineFetcher(f LineFetcher) (old LineFetcher) {
+	old = lineFetcher
+	lineFetcher = f
+	return old
+}
+
+var caretDiagnosticsEnabled = true
+
+// SetCaretDiagnostics turns caret-underline rendering on or off; it is on by
+// default, but still only fires when a LineFetcher has been installed and
+// the destination looks like a terminal.
+func SetCaretDiagnostics(on bool) {
+	caretDiagnosticsEnabled = on
+}
+
+// buildCaret returns the source line named by loc plus a "^~~~" underline
+// beneath it, or "" if no caret applies (no LineFetcher installed, caret
+// diagnostics turned off, loc has no column, or the line isn't available).
+// It does no I/O of its own; the result is attached to an Entry's Caret
+// field for the Sink to render (or not) however it sees fit.
+func buildCaret(loc Loc) string {
+	if !caretDiagnosticsEnabled || lineFetcher == nil || loc.Line <= 0 || loc.Col <= 0 {
+		return ""
+	}
+	line, ok := lineFetcher(loc.File, loc.Line)
+	if !ok {
+		return ""
+	}
+	width := loc.EndCol - loc.Col
+	if loc.EndLine != 0 && loc.EndLine != loc.Line {
+		width = 0 // span crosses lines; just point at the start
+	}
+	if width < 1 {
+		width = 1
+	}
+	caret := make([]byte, 0, loc.Col-1+width)
+	for i := 1; i < loc.Col; i++ {
+		caret = append(caret, ' ')
+	}
+	caret = append(caret, '^')
+	for i := 1; i < width; i++ {
+		caret = append(caret, '~')
+	}
+	return line + "\n" + string(caret)
+}
+
+// isTerminal is a cheap, dependency-free approximation of "is this probably
+// an interactive terminal": good enough to decide whether to draw carets.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
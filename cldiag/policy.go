@@ -0,0 +1,100 @@
+package cldiag
+
+import "flag"
+
+var (
+	warningsAreErrors bool
+	maxWarnings       int
+	suppressedTags    = map[string]bool{}
+	promotedTags      = map[string]bool{}
+)
+
+// SetWarningsAreErrors turns every future Warn[If][2] call into one that is
+// emitted at SeverityError (with tag "error", unless the caller already gave
+// a tag) and, when AtExit is called, forces the program to die.
+func SetWarningsAreErrors(on bool) {
+	warningsAreErrors = on
+}
+
+// SetMaxWarnings sets the number of warnings a program may report before
+// Warn[If][2] gives up and calls Die2 itself.  A value of 0 (the default)
+// means no limit.
+func SetMaxWarnings(n int) {
+	maxWarnings = n
+}
+
+// SuppressWarning silences every future Warn[If][2] call made with the given
+// tag: the message is dropped entirely and does not count towards
+// NumberOfWarnings, as if it had never been called.  It undoes any earlier
+// PromoteWarning(key).
+func SuppressWarning(key string) {
+	suppressedTags[key] = true
+	delete(promotedTags, key)
+}
+
+// PromoteWarning makes every future Warn[If][2] call made with the given tag
+// be emitted at SeverityError, as SetWarningsAreErrors(true) would, regardless
+// of the global setting.  It does not make AtExit force the program to die;
+// only SetWarningsAreErrors(true) does that.  It undoes any earlier
+// SuppressWarning(key).
+func PromoteWarning(key string) {
+	promotedTags[key] = true
+	delete(suppressedTags, key)
+}
+
+// warnAsError reports whether a Warn2 call with the given tag should be
+// treated as an error because of SetWarningsAreErrors or PromoteWarning.
+func warnAsError(tag string) bool {
+	return warningsAreErrors || promotedTags[tag]
+}
+
+// AtExit forces the program to die if SetWarningsAreErrors(true) is in
+// effect and at least one warning was reported.  Programs that call
+// SetWarningsAreErrors should arrange for AtExit to run just before falling
+// out of main(), eg with
+//	defer cldiag.AtExit()
+// (subject to the same “Dying is Dangerous” caveats as Die[If][2] itself).
+func AtExit() {
+	if warningsAreErrors && nWarnings > 0 {
+		Die("")
+	}
+}
+
+/*================================== Policy ==================================*/
+
+// A Policy bundles up the settings above so they can be built once (eg from
+// command-line flags) and applied together.
+type Policy struct {
+	WarningsAreErrors bool
+	MaxWarnings       int
+	Suppress          []string // tags to pass to SuppressWarning
+	Promote           []string // tags to pass to PromoteWarning
+}
+
+// Apply installs p as the current warning policy.
+func (p Policy) Apply() {
+	SetWarningsAreErrors(p.WarningsAreErrors)
+	SetMaxWarnings(p.MaxWarnings)
+	for _, tag := range p.Suppress {
+		SuppressWarning(tag)
+	}
+	for _, tag := range p.Promote {
+		PromoteWarning(tag)
+	}
+}
+
+// RegisterFlags adds -Werror, -Wmax, -Wno and -Wpromote flags to fs, storing
+// the result in p; call p.Apply() after fs.Parse(). The last two are the
+// GCC-style -Wno-foo / -Werror=foo idea, but spelled as ordinary flag.Func
+// flags (-Wno=foo, -Wpromote=foo) since the flag package has no syntax for
+// flag-name suffixes.
+func (p *Policy) RegisterFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&p.WarningsAreErrors, "Werror", p.WarningsAreErrors,
+		"treat every warning as a fatal error")
+	fs.IntVar(&p.MaxWarnings, "Wmax", p.MaxWarnings,
+		"abort after this many warnings (0 = no limit)")
+	fs.Func("Wno", "suppress warnings tagged `tag` (may be repeated)",
+		func(tag string) error { p.Suppress = append(p.Suppress, tag); return nil })
+	fs.Func("Wpromote", "treat warnings tagged `tag` as errors (may be repeated)",
+		func(tag string) error { p.Promote = append(p.Promote, tag); return nil })
+}
@@ -0,0 +1,327 @@
+package cldiag
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+/*================================== Severity ==================================*/
+
+// Severity identifies how serious a diagnostic is, following the eight
+// syslog/sd-daemon levels (see sd-daemon(3)'s <0> .. <7> prefixes).
+//
+type Severity int
+
+const (
+	SeverityDebug Severity = iota
+	SeverityInfo
+	SeverityNotice
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+	SeverityAlert
+	SeverityEmergency
+)
+
+// severityNames and severitySDPrefixes are indexed by Severity.
+var severityNames = [...]string{
+	"debug", "info", "notice", "warning", "error", "critical", "alert", "emergency",
+}
+
+// severitySDPrefixes gives the sd-daemon prefix (<0> .. <7>) for each Severity,
+// following syslog numbering (EMERG=0 .. DEBUG=7), which runs the opposite way
+// from our Severity values.
+var severitySDPrefixes = [...]string{
+	"<7>", "<6>", "<5>", "<4>", "<3>", "<2>", "<1>", "<0>",
+}
+
+// String returns the lower-case name of a Severity, eg "warning" or "critical".
+func (s Severity) String() string {
+	if s < SeverityDebug || s > SeverityEmergency {
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+	return severityNames[s]
+}
+
+// sdPrefix returns the systemd/sd-daemon numeric prefix, eg "<4>", for s.
+func (s Severity) sdPrefix() string {
+	if s < SeverityDebug || s > SeverityEmergency {
+		return ""
+	}
+	return severitySDPrefixes[s]
+}
+
+// ParseSeverity converts a name such as "notice" or "ERROR" into a Severity.
+// It returns an error if name does not match one of the eight levels.
+func ParseSeverity(name string) (Severity, error) {
+	lower := strings.ToLower(name)
+	for i, n := range severityNames {
+		if n == lower {
+			return Severity(i), nil
+		}
+	}
+	return 0, fmt.Errorf("cldiag: %q is not a severity name", name)
+}
+
+/*------------------------------ Level filtering ------------------------------*/
+
+var minSeverity = SeverityDebug
+
+// SetLevel sets the minimum Severity that will actually be emitted; anything
+// less severe is silently dropped (Warn[If][2] does not count dropped
+// warnings towards NumberOfWarnings).  It panics if name is not one of the
+// eight severity names ("debug" .. "emergency").
+func SetLevel(name string) {
+	sev, err := ParseSeverity(name)
+	if err != nil {
+		Panic("%s", err)
+	}
+	minSeverity = sev
+}
+
+// GetLevel returns the Severity set by SetLevel (SeverityDebug, ie "let
+// everything through", by default).
+func GetLevel() Severity {
+	return minSeverity
+}
+
+func severityEnabled(sev Severity) bool {
+	return sev >= minSeverity
+}
+
+/*------------------------------ Per-severity sinks ------------------------------*/
+
+var severityWriters = map[Severity]io.Writer{}
+
+// SetSeverityWriter sends every future message of the given Severity to w
+// instead of the usual destination (os.Stderr, falling back as described for
+// WriteMessage2). Passing w == nil restores the default destination.
+func SetSeverityWriter(sev Severity, w io.Writer) {
+	if w == nil {
+		delete(severityWriters, sev)
+	} else {
+		severityWriters[sev] = w
+	}
+}
+
+func writerForSeverity(sev Severity) io.Writer {
+	if w, ok := severityWriters[sev]; ok {
+		return w
+	}
+	return os.Stderr
+}
+
+// sdPrefixesEnabled controls whether rendered messages are given a leading
+// "<N>" sd-daemon priority prefix (see sd-daemon(3)), for consumption by
+// journald or similar.  Off by default, since it would otherwise clutter
+// output read by a human at a terminal.
+var sdPrefixesEnabled = false
+
+// SetSDPrefixes turns the leading "<N>" sd-daemon priority prefix on or off.
+func SetSDPrefixes(on bool) {
+	sdPrefixesEnabled = on
+}
+
+/*==================================== Sink ====================================*/
+
+// An Entry is one rendered diagnostic, handed to a Sink for output.
+type Entry struct {
+	Prog          string   // the program name (or whatever SetPrefix set)
+	Severity      Severity // how serious this diagnostic is
+	Tag           string   // the optional tag argument, or ""
+	Message       string   // the fmt.Sprintf'd text, with no trailing newline
+	Loc           *Loc     // set by the At-variant diagnostics, else nil
+	WarningsSoFar int      // NumberOfWarnings() at the time of this Entry (for a warning, including itself)
+	Fields        map[string]interface{}
+	Writer        io.Writer
+	Caret         string // the source line + "^~~~" underline for a Loc, or ""; see SetLineFetcher
+}
+
+// Text renders e using the currently-installed Encoder (see SetEncoder);
+// with no encoder installed, this reproduces the original WriteMessage2
+// text, "<prog>[ <tag>]: <message>".
+func (e Entry) Text() string {
+	return currentEncoder.Encode(e.toRecord())
+}
+
+// PlainText renders e as TextEncoder would, regardless of the installed
+// Encoder. It's what the /dev/tty fallback path uses: a failed write to
+// stderr usually means an interactive user is watching, who wants human text
+// even if the program was otherwise emitting JSON for a log shipper.
+func (e Entry) PlainText() string {
+	return TextEncoder{}.Encode(e.toRecord())
+}
+
+// A Sink is where rendered diagnostics go.  The default Sink reproduces the
+// original WriteMessage2 fallback chain (os.Stderr, then /dev/tty, then
+// syslog); programs can install others -- a JSON/journal sink, a sink that
+// records Entries for use in tests, or a fan-out of several sinks -- with
+// SetSink.
+type Sink interface {
+	Write(Entry) error
+}
+
+var currentSink Sink = defaultSink
+
+// SetSink installs a new Sink for all future diagnostics and returns the
+// previous one, so callers can restore it later (tests in particular will
+// want to `defer cldiag.SetSink(cldiag.SetSink(myTestSink))`).
+func SetSink(s Sink) Sink {
+	old := currentSink
+	currentSink = s
+	return old
+}
+
+// A RecordingSink appends every Entry it receives to Entries instead of
+// writing it anywhere; it is meant for use in tests.
+type RecordingSink struct {
+	Entries []Entry
+}
+
+// NewRecordingSink returns a ready-to-use *RecordingSink.
+func NewRecordingSink() *RecordingSink {
+	return &RecordingSink{}
+}
+
+func (s *RecordingSink) Write(e Entry) error {
+	s.Entries = append(s.Entries, e)
+	return nil
+}
+
+// A MultiSink fans a single Entry out to several Sinks, in order.  It
+// returns the first error encountered, if any, but still writes to every
+// sink in the list.
+type MultiSink []Sink
+
+func (m MultiSink) Write(e Entry) error {
+	var firstErr error
+	for _, s := range m {
+		if err := s.Write(e); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+/*============================ Shared emit machinery ============================*/
+
+func renderEntry(sev Severity, tag, format string, fmtArgs ...interface{}) Entry {
+	return Entry{
+		Prog:          msgPrefix,
+		Severity:      sev,
+		Tag:           tag,
+		Message:       fmt.Sprintf(format, fmtArgs...),
+		WarningsSoFar: nWarnings,
+		Writer:        writerForSeverity(sev),
+	}
+}
+
+// StructuredFields lets an error type -- *clerrs.CannotError, in particular
+// -- expose its components as a set of named fields instead of a single
+// flattened string.  When WarnIf[2]/DieIf[2] are given a skipIfNil value
+// implementing this interface, JSONEncoder and LogfmtEncoder render those
+// fields instead of the Sprintf'd Message.
+type StructuredFields interface {
+	CldiagFields() map[string]interface{}
+}
+
+// emit renders and writes a diagnostic of the given Severity, honouring
+// SetLevel filtering.  It reports whether the message passed that filter and
+// was handed to the Sink (a Sink error is not reported).
+func emit(sev Severity, tag, format string, fmtArgs ...interface{}) bool {
+	return emitSrc(sev, tag, nil, format, fmtArgs...)
+}
+
+// emitSrc is like emit, but also takes the original skipIfNil value (if any)
+// so its StructuredFields, when it implements that interface, can be
+// attached to the rendered Entry.
+func emitSrc(sev Severity, tag string, src interface{}, format string, fmtArgs ...interface{}) bool {
+	return emitEntry(sev, nil, tag, src, format, fmtArgs...)
+}
+
+// emitEntry is the shared choke point behind emit/emitSrc and warnLoc/dieLoc:
+// it builds the Entry (located, with a caret, if loc != nil), attaches src's
+// StructuredFields if any, and hands the result to the current Sink. It
+// reports whether the message passed SetLevel filtering; the Sink's own
+// error, if any, is discarded.
+func emitEntry(sev Severity, loc *Loc, tag string, src interface{}, format string, fmtArgs ...interface{}) bool {
+	if !severityEnabled(sev) {
+		return false
+	}
+	var entry Entry
+	if loc != nil {
+		entry = renderEntryAt(sev, *loc, tag, format, fmtArgs...)
+		entry.Caret = buildCaret(*loc)
+	} else {
+		entry = renderEntry(sev, tag, format, fmtArgs...)
+	}
+	entry.Message = strings.TrimSuffix(entry.Message, "\n")
+	if sf, ok := src.(StructuredFields); ok {
+		entry.Fields = sf.CldiagFields()
+	}
+	currentSink.Write(entry)
+	return true
+}
+
+/*============================ Ladder convenience functions ============================*/
+
+// Debug writes a debug-level diagnostic.
+func Debug(format string, fmtArgs ...interface{}) { Debug2("", format, fmtArgs...) }
+
+// Debug2 writes a debug-level diagnostic with an optional tag.
+func Debug2(tag, format string, fmtArgs ...interface{}) {
+	emit(SeverityDebug, tag, format, fmtArgs...)
+}
+
+// Info writes an info-level diagnostic. (WriteMessage/WriteMessage2 are kept
+// as aliases for backward compatibility.)
+func Info(format string, fmtArgs ...interface{}) { Info2("", format, fmtArgs...) }
+
+// Info2 writes an info-level diagnostic with an optional tag.
+func Info2(tag, format string, fmtArgs ...interface{}) {
+	emit(SeverityInfo, tag, format, fmtArgs...)
+}
+
+// Notice writes a notice-level diagnostic: more important than Info, but not
+// a problem in itself.
+func Notice(format string, fmtArgs ...interface{}) { Notice2("", format, fmtArgs...) }
+
+// Notice2 writes a notice-level diagnostic with an optional tag.
+func Notice2(tag, format string, fmtArgs ...interface{}) {
+	emit(SeverityNotice, tag, format, fmtArgs...)
+}
+
+// Error writes an error-level diagnostic without incrementing the warning
+// counter or exiting; use Die[If][2] when the program cannot continue.
+func Error(format string, fmtArgs ...interface{}) { Error2("", format, fmtArgs...) }
+
+// Error2 writes an error-level diagnostic with an optional tag.
+func Error2(tag, format string, fmtArgs ...interface{}) {
+	emit(SeverityError, tag, format, fmtArgs...)
+}
+
+// Critical writes a critical-level diagnostic.
+func Critical(format string, fmtArgs ...interface{}) { Critical2("", format, fmtArgs...) }
+
+// Critical2 writes a critical-level diagnostic with an optional tag.
+func Critical2(tag, format string, fmtArgs ...interface{}) {
+	emit(SeverityCritical, tag, format, fmtArgs...)
+}
+
+// Alert writes an alert-level diagnostic: action must be taken immediately.
+func Alert(format string, fmtArgs ...interface{}) { Alert2("", format, fmtArgs...) }
+
+// Alert2 writes an alert-level diagnostic with an optional tag.
+func Alert2(tag, format string, fmtArgs ...interface{}) {
+	emit(SeverityAlert, tag, format, fmtArgs...)
+}
+
+// Emergency writes an emergency-level diagnostic: the system is unusable.
+func Emergency(format string, fmtArgs ...interface{}) { Emergency2("", format, fmtArgs...) }
+
+// Emergency2 writes an emergency-level diagnostic with an optional tag.
+func Emergency2(tag, format string, fmtArgs ...interface{}) {
+	emit(SeverityEmergency, tag, format, fmtArgs...)
+}
@@ -4,9 +4,9 @@ package cldiag
 
 import (
 	"fmt"
+	"io"
 	"log/syslog"
 	"os"
-	"strings"
 	"syscall"
 )
 
@@ -15,27 +15,65 @@ var altDest *os.File
 // WriteMessage2 writes an informational message (as opposed to a warning or
 // fatal error message), with an optional tag between the prefix and the ":".
 //
+// It is now a thin, severity-filtered wrapper around the Sink installed by
+// SetSink (a posixFallbackSink by default); see that type for the stderr ->
+// /dev/tty -> syslog fallback chain this function used to implement inline.
 func WriteMessage2(tag, format string, v ...interface{}) {
-	t := new(strings.Builder)
-	t.WriteString(msgPrefix)
-	if tag == "" {
-		t.WriteRune(' ')
-		t.WriteString(tag)
+	emit(SeverityInfo, tag, format, v...)
+}
+
+// posixFallbackSink is the default Sink on POSIX systems: write to the
+// Entry's Writer (os.Stderr unless SetSeverityWriter said otherwise). The
+// stderr -> /dev/tty -> syslog fallback chain below only applies when that
+// Writer is an *os.File (normally os.Stderr itself): an arbitrary io.Writer
+// installed via SetSeverityWriter (a bytes.Buffer, a JSON sink's pipe, a
+// test double, ...) is written to directly and its error, if any, returned
+// as-is, since falling back to the user's terminal would be the wrong
+// behaviour for a destination the caller chose deliberately.
+type posixFallbackSink struct{}
+
+var defaultSink Sink = posixFallbackSink{}
+
+func (posixFallbackSink) Write(e Entry) error {
+	text := e.Text() + "\n"
+	// The /dev/tty and syslog fallbacks below always use PlainText: a failed
+	// write to stderr usually means an interactive user is watching, who
+	// wants human text even if the installed Encoder is JSON/logfmt for a
+	// log shipper reading stderr under normal conditions.
+	plainText := e.PlainText() + "\n"
+
+	dest := e.Writer
+	if dest == nil {
+		dest = os.Stderr
+	}
+	destFile, isFile := dest.(*os.File)
+	if !isFile {
+		_, err := io.WriteString(dest, text)
+		return err
+	}
+
+	// The caret is only added to a destFile that's actually a terminal, and
+	// only when TextEncoder is installed: appending "^~~~" art to a
+	// JSON/logfmt stream would corrupt it for whatever's reading the other
+	// end, even though that stream happens to be a *os.File.
+	if e.Caret != "" && isTerminal(destFile) {
+		if _, plain := currentEncoder.(TextEncoder); plain {
+			text += e.Caret + "\n"
+		}
 	}
-	fmt.Fprintf(t, ": "+format+"\n", v...)
-	text := t.String()
-	if l := len(text); text[l-2] == '\n' {
-		text = text[:l-1]
+	// The /dev/tty fallback is always a real terminal showing PlainText, so
+	// the caret belongs there regardless of the installed Encoder.
+	if e.Caret != "" {
+		plainText += e.Caret + "\n"
 	}
-	t = nil
 
 	verb := "write to"
 	if altDest == nil { // this is the usual case
-		_, err := os.Stderr.WriteString(text)
+		_, err := io.WriteString(destFile, text)
 		if err != nil {
 			err = TidyError(err)
 		} else {
-			err = os.Stderr.Sync()
+			err = destFile.Sync()
 			if err != nil {
 				err = TidyError(err)
 				if err == syscall.EINVAL {
@@ -47,7 +85,7 @@ func WriteMessage2(tag, format string, v ...interface{}) {
 			}
 		}
 		if err == nil {
-			return // Message successfully written via stderr and synced.
+			return nil // Message successfully written via stderr and synced.
 		}
 
 		// Oops! Use /dev/tty instead of os.Stderr for this and future messages.
@@ -63,10 +101,10 @@ func WriteMessage2(tag, format string, v ...interface{}) {
 			if err3 != nil {
 				// AFAICT, this should not happen.
 				panic(fmt.Sprintf("%s PANIC: %s to report: %s",
-					os.Args[0], text2, text))
+					os.Args[0], text2, plainText))
 			}
 			fmt.Fprintf(syslogWriter, "%s to report: %s",
-				text2, text[:len(text)-1])
+				text2, plainText[:len(plainText)-1])
 			panic(fmt.Sprintf("%s PANIC: %s: more in syslog",
 				os.Args[0], text2))
 		}
@@ -74,5 +112,6 @@ func WriteMessage2(tag, format string, v ...interface{}) {
 			"%s: cannot %s stderr (%s), using /dev/tty instead\n",
 			os.Args[0], verb, err)
 	}
-	altDest.WriteString(text)
+	altDest.WriteString(plainText)
+	return nil
 }
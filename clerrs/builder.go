@@ -0,0 +1,131 @@
+package clerrs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// WithCause sets ce's underlying error (the same field Cannot's last
+// argument sets) and returns ce, for chaining.
+func (ce *CannotError) WithCause(err error) *CannotError {
+	ce.BaseError = err
+	return ce
+}
+
+// WithValue attaches an arbitrary key/value pair to ce, retrievable with
+// clerrs.Value. It returns ce, for chaining.
+func (ce *CannotError) WithValue(key, val interface{}) *CannotError {
+	if ce.values == nil {
+		ce.values = map[interface{}]interface{}{}
+	}
+	ce.values[key] = val
+	return ce
+}
+
+// WithUserMessage sets the text UserError() will return, for display to end
+// users instead of the (potentially too revealing) output of Error(). It
+// returns ce, for chaining.
+func (ce *CannotError) WithUserMessage(s string) *CannotError {
+	ce.userMessage = s
+	return ce
+}
+
+// WithHTTPCode records an HTTP status code alongside ce, retrievable with
+// clerrs.HTTPCode. It returns ce, for chaining.
+func (ce *CannotError) WithHTTPCode(code int) *CannotError {
+	ce.httpCode = code
+	ce.hasHTTPCode = true
+	return ce
+}
+
+// Here captures the file:line of its caller (using runtime.Caller) into ce,
+// so %+v can show where the error was built. It returns ce, for chaining.
+func (ce *CannotError) Here() *CannotError {
+	if _, file, line, ok := runtime.Caller(1); ok {
+		ce.stack = fmt.Sprintf("%s:%d", file, line)
+	}
+	return ce
+}
+
+// UserError returns text suitable for showing to an end user: WithUserMessage's
+// argument if one was set, or otherwise the same text as Error() but with
+// any wrapped BaseError omitted, since that often contains details (paths,
+// raw OS errors) that aren't this package's to share with end users.
+func (ce *CannotError) UserError() string {
+	if ce.userMessage != "" {
+		return ce.userMessage
+	}
+	return ce.headline()
+}
+
+// headline renders ce the way Error() does, but with BaseError omitted. It
+// works on a local copy rather than clearing and restoring ce.BaseError in
+// place, since ce is an ordinary error value that may be read by another
+// goroutine (eg logged) while this one is building this string.
+func (ce *CannotError) headline() string {
+	clone := *ce
+	clone.BaseError = nil
+	return clone.Error()
+}
+
+// Format implements fmt.Formatter, so %+v on a *CannotError dumps the call site Here() captured, any WithValue pairs,
+// and the full cause chain, in addition to the usual Error() text produced
+// by %v/%s.
+func (ce *CannotError) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if !f.Flag('+') {
+			io.WriteString(f, ce.Error())
+			return
+		}
+		// headline() omits BaseError here since it's printed in full as the
+		// first entry of the "caused by" chain below.
+		io.WriteString(f, ce.headline())
+		if ce.stack != "" {
+			fmt.Fprintf(f, "\n\tat %s", ce.stack)
+		}
+		for k, v := range ce.values {
+			fmt.Fprintf(f, "\n\t%v=%v", k, v)
+		}
+		if ce.hasHTTPCode {
+			fmt.Fprintf(f, "\n\thttp status %d", ce.httpCode)
+		}
+		for cause := errors.Unwrap(error(ce)); cause != nil; cause = errors.Unwrap(cause) {
+			fmt.Fprintf(f, "\n\tcaused by: %s", cause.Error())
+		}
+	case 's':
+		io.WriteString(f, ce.Error())
+	case 'q':
+		fmt.Fprintf(f, "%q", ce.Error())
+	}
+}
+
+// HTTPCode walks err's Unwrap() chain for a *CannotError carrying an HTTP
+// status set by WithHTTPCode, and returns it along with true. If none is
+// found, it returns (0, false).
+func HTTPCode(err error) (int, bool) {
+	for err != nil {
+		if ce, ok := err.(*CannotError); ok && ce.hasHTTPCode {
+			return ce.httpCode, true
+		}
+		err = errors.Unwrap(err)
+	}
+	return 0, false
+}
+
+// Value walks err's Unwrap() chain for a *CannotError carrying the given key
+// (set by WithValue), and returns its value along with true. If none is
+// found, it returns (nil, false).
+func Value(err error, key interface{}) (interface{}, bool) {
+	for err != nil {
+		if ce, ok := err.(*CannotError); ok {
+			if v, found := ce.values[key]; found {
+				return v, true
+			}
+		}
+		err = errors.Unwrap(err)
+	}
+	return nil, false
+}
@@ -30,6 +30,15 @@ type CannotError struct {
 	QuoteNoun bool   // Whether to put .Noun in double quotes
 	Suffix    string // Text to go after the noun, or ""
 	BaseError error  // The underlying error, if any
+	Tag       string // Catalogue key naming the action, eg "open.config"; see Formatter
+
+	// The following are set by the fluent With*/Here methods in builder.go,
+	// rather than by Cannot(), and do not affect Error()'s rendering.
+	userMessage string
+	httpCode    int
+	hasHTTPCode bool
+	stack       string
+	values      map[interface{}]interface{}
 }
 
 // Cannot() is a convenience function to produce a (pointer to a) CannotError value.
@@ -53,7 +62,21 @@ func Cannot(
 }
 
 // Pointers to CannotError values satisfy the error interface.
+//
+// The text is produced by the installed Formatter (see SetDefaultFormatter);
+// with no Formatter installed, this is the English template
+//	cannot <verb>[ <adjective>] <o-q-noun>[ <suffix>][: <base-error>]
+// described above, regardless of Tag.
 func (ce *CannotError) Error() string {
+	if defaultFormatter != nil {
+		return defaultFormatter.Format(ce)
+	}
+	return englishFormatter{}.Format(ce)
+}
+
+// renderEnglish is the formatting logic behind englishFormatter; it is also
+// what TemplateFormatter falls back on for a Tag with no matching template.
+func renderEnglish(ce *CannotError) string {
 	var b strings.Builder
 	b.WriteString("cannot " + ce.Verb + " ")
 	if ce.Adjective != "" {
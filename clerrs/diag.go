@@ -0,0 +1,20 @@
+package clerrs
+
+// CldiagFields implements cldiag.StructuredFields, so a *CannotError passed
+// as WarnIf[2]/DieIf[2]'s skipIfNil argument is rendered by JSONEncoder and
+// LogfmtEncoder as structured fields instead of a flattened Error() string.
+func (ce *CannotError) CldiagFields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"verb":      ce.Verb,
+		"adjective": ce.Adjective,
+		"noun":      ce.Noun,
+		"suffix":    ce.Suffix,
+	}
+	if ce.Tag != "" {
+		fields["tag"] = ce.Tag
+	}
+	if ce.BaseError != nil {
+		fields["cause"] = ce.BaseError.Error()
+	}
+	return fields
+}
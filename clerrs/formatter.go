@@ -0,0 +1,102 @@
+package clerrs
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/c12h/command-line-utils/cldiag"
+)
+
+// A Formatter turns a *CannotError into its Error() text. SetDefaultFormatter
+// installs one for every CannotError in the program.
+type Formatter interface {
+	Format(ce *CannotError) string
+}
+
+// defaultFormatter is nil until SetDefaultFormatter is called, so that
+// Error() keeps producing exactly its original text when no-one has opted
+// in to the new machinery.
+var defaultFormatter Formatter
+
+// SetDefaultFormatter installs f as the Formatter used by every
+// (*CannotError).Error() call, and returns the previous one (nil if none was
+// installed). Passing nil restores the original hard-coded English text.
+func SetDefaultFormatter(f Formatter) Formatter {
+	old := defaultFormatter
+	defaultFormatter = f
+	return old
+}
+
+// englishFormatter reproduces the package's original, hard-coded English
+// rendering; it is used whenever no other Formatter has been installed.
+type englishFormatter struct{}
+
+func (englishFormatter) Format(ce *CannotError) string { return renderEnglish(ce) }
+
+/*============================ TemplateFormatter ============================*/
+
+// templateData is what a TemplateFormatter's templates actually see: the
+// same information as CannotError, but with BaseError already run through
+// cldiag.TidyError and reduced to a string (and a HasBase flag), since
+// templates can't usefully call methods on an arbitrary error value.
+type templateData struct {
+	Verb, Adjective, Noun, Suffix, Tag string
+	QuoteNoun                          bool
+	HasBase                            bool
+	Base                               string
+}
+
+func (ce *CannotError) templateData() templateData {
+	d := templateData{
+		Verb:      ce.Verb,
+		Adjective: ce.Adjective,
+		Noun:      ce.Noun,
+		QuoteNoun: ce.QuoteNoun,
+		Suffix:    ce.Suffix,
+		Tag:       ce.Tag,
+	}
+	if ce.BaseError != nil {
+		d.HasBase = true
+		d.Base = cldiag.TidyError(ce.BaseError).Error()
+	}
+	return d
+}
+
+// A TemplateFormatter picks a text/template by CannotError.Tag -- intended
+// to come from a message catalogue, one entry per Tag, loaded from a
+// translation file -- and falls back to the package's English rendering for
+// any CannotError whose Tag has no matching template (including "").
+type TemplateFormatter struct {
+	Templates map[string]*template.Template
+}
+
+// NewTemplateFormatter parses one template per catalogue entry (catalogue
+// maps Tag -> a text/template source such as
+//	"impossible d'ouvrir {{if .QuoteNoun}}{{printf \"%q\" .Noun}}{{else}}{{.Noun}}{{end}}{{if .HasBase}} : {{.Base}}{{end}}"
+// ) and returns a ready-to-use *TemplateFormatter, or the first parse error.
+func NewTemplateFormatter(catalogue map[string]string) (*TemplateFormatter, error) {
+	tf := &TemplateFormatter{Templates: make(map[string]*template.Template, len(catalogue))}
+	for tag, src := range catalogue {
+		tmpl, err := template.New(tag).Parse(src)
+		if err != nil {
+			return nil, err
+		}
+		tf.Templates[tag] = tmpl
+	}
+	return tf, nil
+}
+
+// Format implements Formatter, picking the template for ce.Tag (falling back
+// to the package's English rendering if there is none, or if the template
+// fails to execute).
+func (tf *TemplateFormatter) Format(ce *CannotError) string {
+	tmpl := tf.Templates[ce.Tag]
+	if tmpl == nil {
+		return renderEnglish(ce)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, ce.templateData()); err != nil {
+		return renderEnglish(ce)
+	}
+	return b.String()
+}